@@ -0,0 +1,89 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"github.com/astaxie/beego/orm"
+
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddAccessLog persists a single access log record.
+func AddAccessLog(accessLog models.AccessLog) error {
+	o := orm.NewOrm()
+	_, err := o.Insert(&accessLog)
+	return err
+}
+
+func accessLogQuerySeter(o orm.Ormer, query *models.LogQueryParam) orm.QuerySeter {
+	qs := o.QueryTable("access_log").Filter("ProjectID__in", query.ProjectIDs)
+
+	if len(query.Username) > 0 {
+		qs = qs.Filter("Username__icontains", query.Username)
+	}
+	if len(query.Repository) > 0 {
+		qs = qs.Filter("RepoName__icontains", query.Repository)
+	}
+	if len(query.Tag) > 0 {
+		qs = qs.Filter("RepoTag__icontains", query.Tag)
+	}
+	if len(query.Operations) > 0 {
+		qs = qs.Filter("Operation__in", query.Operations)
+	}
+	if query.BeginTime != nil {
+		qs = qs.Filter("OpTime__gte", *query.BeginTime)
+	}
+	if query.EndTime != nil {
+		qs = qs.Filter("OpTime__lte", *query.EndTime)
+	}
+
+	return qs
+}
+
+// GetTotalOfAccessLogs returns the number of access log records matching
+// query, ignoring its Pagination.
+func GetTotalOfAccessLogs(query *models.LogQueryParam) (int64, error) {
+	o := orm.NewOrm()
+	return accessLogQuerySeter(o, query).Count()
+}
+
+// GetAccessLogs returns one page of access log records matching query,
+// newest first.
+func GetAccessLogs(query *models.LogQueryParam) ([]models.AccessLog, error) {
+	o := orm.NewOrm()
+	qs := accessLogQuerySeter(o, query).OrderBy("-OpTime")
+
+	if query.Pagination != nil {
+		qs = qs.Limit(query.Pagination.Size, (query.Pagination.Page-1)*query.Pagination.Size)
+	}
+
+	var logs []models.AccessLog
+	_, err := qs.All(&logs)
+	return logs, err
+}
+
+// GetAccessLogsAfter returns up to limit access log records matching query
+// with LogID > afterID, ordered by LogID ascending. It is the cursor
+// primitive ExportAccessLog walks: unlike GetAccessLogs' offset-based
+// Pagination, a keyset cursor keeps each page stable in the face of
+// concurrent inserts, so a long export can't skip or duplicate rows.
+func GetAccessLogsAfter(query *models.LogQueryParam, afterID int64, limit int) ([]models.AccessLog, error) {
+	o := orm.NewOrm()
+	qs := accessLogQuerySeter(o, query).Filter("LogID__gt", afterID).OrderBy("LogID").Limit(limit)
+
+	var logs []models.AccessLog
+	_, err := qs.All(&logs)
+	return logs, err
+}
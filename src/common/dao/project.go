@@ -0,0 +1,202 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/orm"
+
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// GetRepositoryByProjectName returns the names of the repositories that
+// belong to the project named name.
+func GetRepositoryByProjectName(name string) ([]string, error) {
+	o := orm.NewOrm()
+
+	var repos []string
+	_, err := o.Raw(`select name from repository where project_id = (
+		select project_id from project where name = ?)`, name).QueryRows(&repos)
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// GetRepPolicyByProject returns the replication policies configured on the
+// project with the given id.
+func GetRepPolicyByProject(id int64) ([]*models.RepPolicy, error) {
+	o := orm.NewOrm()
+
+	var policies []*models.RepPolicy
+	_, err := o.QueryTable("replication_policy").Filter("ProjectID", id).All(&policies)
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetProjectStorageUsage returns the aggregate size, in bytes, of the blobs
+// referenced by every repository in the project with the given id.
+func GetProjectStorageUsage(id int64) (int64, error) {
+	o := orm.NewOrm()
+
+	var usage int64
+	err := o.Raw(`select coalesce(sum(size), 0) from blob b
+		join repository r on r.repository_id = b.repository_id
+		where r.project_id = ?`, id).QueryRow(&usage)
+	if err != nil {
+		return 0, err
+	}
+	return usage, nil
+}
+
+// GetTagsByRepo returns the tags currently pushed to repo.
+func GetTagsByRepo(repo string) ([]string, error) {
+	o := orm.NewOrm()
+
+	var tags []string
+	_, err := o.Raw(`select tag from repository_tag where repository_name = ?`, repo).QueryRows(&tags)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetProjectByName returns the project named name, or nil if no such
+// project exists. It backs the registry notification handler's quota
+// checks, which need to resolve the pushed repository's project before
+// CheckRepoQuota/CheckPushQuota can be applied.
+func GetProjectByName(name string) (*models.Project, error) {
+	o := orm.NewOrm()
+
+	project := &models.Project{Name: name}
+	err := o.Read(project, "Name")
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// GetProjectQuotaLimits returns the repo/tag/storage limits configured on
+// the project with the given id, read directly rather than through the
+// Project struct ProjectMgr.Get/GetAll return: ProjectMgr's SELECT predates
+// these columns, so a value off p.project.RepoLimit et al. is not trustworthy.
+func GetProjectQuotaLimits(id int64) (repoLimit, tagLimit, storageLimit int64, err error) {
+	o := orm.NewOrm()
+	err = o.Raw(`select repo_limit, tag_limit, storage_limit from project where project_id = ?`, id).
+		QueryRow(&repoLimit, &tagLimit, &storageLimit)
+	return
+}
+
+// UpdateProjectQuota writes the repo/tag/storage limits of the project with
+// the given id. Like SoftDeleteProject, it goes through a dedicated raw
+// update rather than the general-purpose ProjectMgr.Update, whose column
+// set predates these columns and can't be trusted to persist them.
+func UpdateProjectQuota(id, repoLimit, tagLimit, storageLimit int64) error {
+	o := orm.NewOrm()
+	_, err := o.Raw(`update project set repo_limit = ?, tag_limit = ?, storage_limit = ? where project_id = ?`,
+		repoLimit, tagLimit, storageLimit, id).Exec()
+	return err
+}
+
+// GetProjectByIDIncludeDeleted returns the project with the given id,
+// whether or not it has been soft-deleted. It backs the restore endpoint,
+// which is the one place that must still be able to look up a project
+// sitting in the recycle bin.
+func GetProjectByIDIncludeDeleted(id int64) (*models.Project, error) {
+	o := orm.NewOrm()
+
+	project := &models.Project{ProjectID: id}
+	err := o.Read(project)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// SoftDeleteProject marks the project with the given id as deleted at
+// deletedAt. It always writes both the deleted and deleted_at columns,
+// explicitly, rather than going through the general-purpose ProjectMgr
+// Update path, so a restore's zero-value DeletedAt below is guaranteed to
+// land instead of silently being dropped as a no-op update.
+func SoftDeleteProject(id int64, deletedAt time.Time) error {
+	o := orm.NewOrm()
+
+	_, err := o.Raw(`update project set deleted = ?, deleted_at = ? where project_id = ?`,
+		true, deletedAt, id).Exec()
+	return err
+}
+
+// RestoreProject undoes SoftDeleteProject, clearing both the deleted and
+// deleted_at columns.
+func RestoreProject(id int64) error {
+	o := orm.NewOrm()
+
+	_, err := o.Raw(`update project set deleted = ?, deleted_at = ? where project_id = ?`,
+		false, time.Time{}, id).Exec()
+	return err
+}
+
+// GetDeletedProjectsBefore returns the projects that were soft-deleted
+// before the given cutoff time, for PurgeDeletedProjects to hard-delete.
+func GetDeletedProjectsBefore(cutoff time.Time) ([]*models.Project, error) {
+	o := orm.NewOrm()
+
+	var projects []*models.Project
+	_, err := o.QueryTable("project").
+		Filter("Deleted", true).
+		Filter("DeletedAt__lt", cutoff).
+		All(&projects)
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// PurgeProject hard-deletes the project with the given id along with its
+// repositories, tags, replication policies and access logs. It is only
+// ever called, by PurgeDeletedProjects, on a project that's already been
+// sitting soft-deleted past the recycle-bin retention window.
+func PurgeProject(id int64) error {
+	o := orm.NewOrm()
+
+	if err := o.Begin(); err != nil {
+		return err
+	}
+
+	for _, stmt := range []string{
+		`delete from repository_tag where repository_name in (
+			select name from repository where project_id = ?)`,
+		`delete from repository where project_id = ?`,
+		`delete from replication_policy where project_id = ?`,
+		`delete from access_log where project_id = ?`,
+		`delete from project where project_id = ?`,
+	} {
+		if _, err := o.Raw(stmt, id).Exec(); err != nil {
+			o.Rollback()
+			return err
+		}
+	}
+
+	return o.Commit()
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import "time"
+
+// AccessLog holds a single record of an action taken against a project,
+// e.g. a push, pull, create, delete or restore.
+type AccessLog struct {
+	LogID     int64     `orm:"column(log_id)" json:"log_id"`
+	Username  string    `orm:"column(username)" json:"username"`
+	ProjectID int64     `orm:"column(project_id)" json:"project_id"`
+	RepoName  string    `orm:"column(repo_name)" json:"repo_name"`
+	RepoTag   string    `orm:"column(repo_tag)" json:"repo_tag"`
+	Operation string    `orm:"column(operation)" json:"operation"`
+	OpTime    time.Time `orm:"column(op_time)" json:"op_time"`
+
+	// BeginTimestamp, EndTimestamp and Keywords are request-only fields:
+	// they're populated when AccessLog is used to decode a filter query,
+	// never when it represents a stored record.
+	BeginTimestamp int64  `json:"begin_timestamp"`
+	EndTimestamp   int64  `json:"end_timestamp"`
+	Keywords       string `json:"keywords"`
+}
+
+// LogQueryParam is used to filter the result of an access log query.
+type LogQueryParam struct {
+	ProjectIDs []int64
+	Username   string
+	Repository string
+	Tag        string
+	Operations []string
+	BeginTime  *time.Time
+	EndTime    *time.Time
+	Pagination *Pagination
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+// Member represents a project member, or, when used inside
+// ProjectQueryParam, a filter for projects a given user is a member of.
+type Member struct {
+	ID        int    `orm:"column(id)" json:"id"`
+	ProjectID int64  `orm:"column(project_id)" json:"project_id"`
+	Role      int    `orm:"column(role)" json:"role_id"`
+	Name      string `json:"entity_name"`
+}
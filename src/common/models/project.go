@@ -0,0 +1,71 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import "time"
+
+// Project holds the details of a project.
+type Project struct {
+	ProjectID    int64     `orm:"column(project_id)" json:"project_id"`
+	OwnerID      int       `orm:"column(owner_id)" json:"owner_id"`
+	Name         string    `orm:"column(name)" json:"name"`
+	OwnerName    string    `json:"owner_name"`
+	Public       int       `orm:"column(public)" json:"public"`
+	Role         int       `json:"current_user_role_id"`
+	Togglable    bool      `json:"togglable"`
+	RepoCount    int       `json:"repo_count"`
+	CreationTime time.Time `orm:"column(creation_time)" json:"creation_time"`
+	UpdateTime   time.Time `orm:"column(update_time)" json:"update_time"`
+
+	// RepoLimit, TagLimit and StorageLimit are the quotas a sysadmin can set
+	// on a project; 0 means unlimited. RepoCount and StorageUsage report
+	// current consumption and are not persisted columns.
+	RepoLimit    int64 `orm:"column(repo_limit)" json:"repo_limit"`
+	TagLimit     int64 `orm:"column(tag_limit)" json:"tag_limit"`
+	StorageLimit int64 `orm:"column(storage_limit)" json:"storage_limit"`
+	StorageUsage int64 `json:"storage_usage"`
+
+	// Deleted and DeletedAt back the project recycle bin: a soft-deleted
+	// project is hidden from List/Get/Head and name-uniqueness checks and
+	// can no longer be pushed to, but isn't hard-deleted (repos, tags,
+	// policies and access logs kept) until PurgeDeletedProjects reaps it.
+	Deleted   bool      `orm:"column(deleted)" json:"deleted"`
+	DeletedAt time.Time `orm:"column(deleted_at)" json:"deleted_at,omitempty"`
+}
+
+// ProjectQueryParam is used to filter the result of Project list.
+type ProjectQueryParam struct {
+	Name       string
+	Public     *bool
+	Member     *Member
+	Pagination *Pagination
+}
+
+// ProjectQuota is the response body of ProjectAPI.GetQuota: the limits
+// configured on a project alongside its current usage.
+type ProjectQuota struct {
+	RepoLimit    int64 `json:"repo_limit"`
+	TagLimit     int64 `json:"tag_limit"`
+	StorageLimit int64 `json:"storage_limit"`
+	RepoUsage    int64 `json:"repo_usage"`
+	StorageUsage int64 `json:"storage_usage"`
+}
+
+// RepPolicy is a minimal reference to a project's replication policies;
+// ProjectAPI only ever needs the count of them.
+type RepPolicy struct {
+	ID        int64 `orm:"column(id)" json:"id"`
+	ProjectID int64 `orm:"column(project_id)" json:"project_id"`
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+// NotificationHandler handles the registry's webhook notifications at
+// POST /service/notifications. It's the push path: every manifest push
+// routes through here before it's accepted, which is where the repo/tag/
+// storage quota checks and the soft-delete recycle-bin check are applied.
+type NotificationHandler struct {
+	BaseController
+}
+
+type registryNotification struct {
+	Events []registryEvent `json:"events"`
+}
+
+type registryEvent struct {
+	Action string `json:"action"`
+	Target struct {
+		Repository string `json:"repository"`
+		Tag        string `json:"tag"`
+		Size       int64  `json:"size"`
+	} `json:"target"`
+}
+
+// Post decodes the registry notification payload and, for every manifest
+// push it carries, rejects the push if the target project has been
+// soft-deleted or would exceed its repo/tag/storage quota.
+func (n *NotificationHandler) Post() {
+	var notification registryNotification
+	if err := json.NewDecoder(n.Ctx.Request.Body).Decode(&notification); err != nil {
+		log.Errorf("failed to decode registry notification: %v", err)
+		n.CustomAbort(http.StatusBadRequest, "")
+		return
+	}
+
+	for _, event := range notification.Events {
+		if event.Action != "push" || len(event.Target.Tag) == 0 {
+			continue
+		}
+
+		if err := enforcePush(event); err != nil {
+			log.Errorf("rejecting push to %s:%s: %v", event.Target.Repository, event.Target.Tag, err)
+			if err == ErrProjectDeleted {
+				n.CustomAbort(http.StatusForbidden, err.Error())
+			} else {
+				n.CustomAbort(http.StatusRequestEntityTooLarge, err.Error())
+			}
+			return
+		}
+	}
+}
+
+// enforcePush applies EnforceNotDeleted and the quota checks to a single
+// push event, looking up the project the pushed repository belongs to.
+func enforcePush(event registryEvent) error {
+	projectName := strings.SplitN(event.Target.Repository, "/", 2)[0]
+	project, err := dao.GetProjectByName(projectName)
+	if err != nil {
+		return err
+	}
+	if project == nil {
+		// the project doesn't exist from this handler's point of view; let
+		// the registry's own auth/path validation reject the push
+		return nil
+	}
+
+	if err := EnforceNotDeleted(project); err != nil {
+		return err
+	}
+
+	repos, err := dao.GetRepositoryByProjectName(project.Name)
+	if err != nil {
+		return err
+	}
+	newRepo := true
+	for _, r := range repos {
+		if r == event.Target.Repository {
+			newRepo = false
+			break
+		}
+	}
+	if newRepo {
+		if err := CheckRepoQuota(project); err != nil {
+			return err
+		}
+	}
+
+	return CheckPushQuota(project, event.Target.Repository, event.Target.Tag, event.Target.Size)
+}
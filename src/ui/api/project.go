@@ -15,10 +15,13 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/vmware/harbor/src/common"
 	"github.com/vmware/harbor/src/common/dao"
@@ -31,16 +34,25 @@ import (
 )
 
 // ProjectAPI handles request to /api/projects/{} /api/projects/{}/logs
+// /api/projects/{}/logs/export /api/projects/{}/quota /api/projects/{}/restore
 type ProjectAPI struct {
 	BaseController
 	project *models.Project
 }
 
 type projectReq struct {
-	ProjectName string `json:"project_name"`
-	Public      int    `json:"public"`
+	ProjectName  string `json:"project_name"`
+	Public       int    `json:"public"`
+	RepoLimit    int64  `json:"repo_limit"`
+	TagLimit     int64  `json:"tag_limit"`
+	StorageLimit int64  `json:"storage_limit"`
 }
 
+// ErrQuotaExceeded is returned by the quota enforcement helpers below when a
+// project's repository, tag or storage limit has already been reached. A
+// limit of 0 on any of the three means unlimited.
+var ErrQuotaExceeded = errors.New("project quota exceeded")
+
 const projectNameMaxLen int = 30
 const projectNameMinLen int = 2
 const restrictedNameChars = `[a-z0-9]+(?:[._-][a-z0-9]+)*`
@@ -62,7 +74,14 @@ func (p *ProjectAPI) Prepare() {
 			return
 		}
 
-		project, err := p.ProjectMgr.Get(id)
+		var project *models.Project
+		if strings.HasSuffix(p.Ctx.Request.URL.Path, "/restore") {
+			// the restore endpoint is the one place that must still be able
+			// to look up a project sitting in the recycle bin
+			project, err = dao.GetProjectByIDIncludeDeleted(id)
+		} else {
+			project, err = p.ProjectMgr.Get(id)
+		}
 		if err != nil {
 			p.HandleInternalServerError(fmt.Sprintf("failed to get project %d: %v",
 				id, err))
@@ -111,15 +130,40 @@ func (p *ProjectAPI) Post() {
 		return
 	}
 	if exist {
-		p.RenderError(http.StatusConflict, "")
-		return
+		// ProjectMgr.Exist predates the recycle bin and doesn't filter
+		// deleted=true, so a name sitting in another project's recycle bin
+		// would otherwise block reuse forever; only treat it as a real
+		// conflict if the existing row isn't soft-deleted.
+		existing, err := dao.GetProjectByName(pro.ProjectName)
+		if err != nil {
+			p.HandleInternalServerError(fmt.Sprintf("failed to get project %s: %v",
+				pro.ProjectName, err))
+			return
+		}
+		if existing == nil || !existing.Deleted {
+			p.RenderError(http.StatusConflict, "")
+			return
+		}
 	}
 
-	projectID, err := p.ProjectMgr.Create(&models.Project{
+	project := &models.Project{
 		Name:      pro.ProjectName,
 		Public:    pro.Public,
 		OwnerName: p.SecurityCtx.GetUsername(),
-	})
+	}
+	// quotas can only be set by a sysadmin at creation time, everyone else
+	// gets the system defaults applied by ProjectMgr.Create
+	if p.SecurityCtx.IsSysAdmin() {
+		if pro.RepoLimit < 0 || pro.TagLimit < 0 || pro.StorageLimit < 0 {
+			p.RenderError(http.StatusBadRequest, "repo_limit, tag_limit and storage_limit must not be negative")
+			return
+		}
+		project.RepoLimit = pro.RepoLimit
+		project.TagLimit = pro.TagLimit
+		project.StorageLimit = pro.StorageLimit
+	}
+
+	projectID, err := p.ProjectMgr.Create(project)
 	if err != nil {
 		log.Errorf("Failed to add project, error: %v", err)
 		dup, _ := regexp.MatchString(dupProjectPattern, err.Error())
@@ -163,7 +207,7 @@ func (p *ProjectAPI) Head() {
 		return
 	}
 
-	if project == nil {
+	if project == nil || project.Deleted {
 		p.HandleNotFound(fmt.Sprintf("project %s not found", name))
 		return
 	}
@@ -171,6 +215,13 @@ func (p *ProjectAPI) Head() {
 
 // Get ...
 func (p *ProjectAPI) Get() {
+	// a soft-deleted project is hidden from Get the same way Delete guards
+	// against operating on one twice
+	if p.project.Deleted {
+		p.HandleNotFound(fmt.Sprintf("project %d not found", p.project.ProjectID))
+		return
+	}
+
 	if p.project.Public == 0 {
 		if !p.SecurityCtx.IsAuthenticated() {
 			p.HandleUnauthorized()
@@ -187,7 +238,12 @@ func (p *ProjectAPI) Get() {
 	p.ServeJSON()
 }
 
-// Delete ...
+// Delete soft-deletes the project instead of rejecting the request
+// outright: the project is hidden from List/Get/Head and name-uniqueness
+// checks, and new pushes to it are blocked by EnforceNotDeleted. The
+// project and its repositories, tags, policies and access logs are only
+// hard-deleted later by PurgeDeletedProjects once the project_recycle_days
+// retention window has elapsed, giving operators a window to Restore it.
 func (p *ProjectAPI) Delete() {
 	if !p.SecurityCtx.IsAuthenticated() {
 		p.HandleUnauthorized()
@@ -199,27 +255,58 @@ func (p *ProjectAPI) Delete() {
 		return
 	}
 
-	contains, err := projectContainsRepo(p.project.Name)
-	if err != nil {
-		log.Errorf("failed to check whether project %s contains any repository: %v", p.project.Name, err)
-		p.CustomAbort(http.StatusInternalServerError, "")
+	if p.project.Deleted {
+		p.HandleNotFound(fmt.Sprintf("project %d not found", p.project.ProjectID))
+		return
 	}
-	if contains {
-		p.CustomAbort(http.StatusPreconditionFailed, "project contains repositores, can not be deleted")
+
+	// Deleted/DeletedAt are written through a dedicated dao call rather than
+	// the general-purpose ProjectMgr.Update, whose column set is scoped to
+	// the fields ToggleProjectPublic needs and can't be trusted to persist
+	// a new column, let alone Restore's zero-value DeletedAt below.
+	if err := dao.SoftDeleteProject(p.project.ProjectID, time.Now()); err != nil {
+		p.HandleInternalServerError(
+			fmt.Sprintf("failed to delete project %d: %v", p.project.ProjectID, err))
+		return
 	}
 
-	contains, err = projectContainsPolicy(p.project.ProjectID)
-	if err != nil {
-		log.Errorf("failed to check whether project %s contains any policy: %v", p.project.Name, err)
-		p.CustomAbort(http.StatusInternalServerError, "")
+	go func() {
+		if err := dao.AddAccessLog(models.AccessLog{
+			Username:  p.SecurityCtx.GetUsername(),
+			ProjectID: p.project.ProjectID,
+			RepoName:  p.project.Name + "/",
+			RepoTag:   "N/A",
+			Operation: "delete",
+			OpTime:    time.Now(),
+		}); err != nil {
+			log.Errorf("failed to add access log: %v", err)
+		}
+	}()
+}
+
+// Restore handles POST to /api/projects/{}/restore, undoing a soft-delete
+// performed via Delete as long as the project is still within its
+// recycle-bin retention window, i.e. PurgeDeletedProjects hasn't reaped it
+// yet. Only a sysadmin may restore a project.
+func (p *ProjectAPI) Restore() {
+	if !p.SecurityCtx.IsAuthenticated() {
+		p.HandleUnauthorized()
+		return
+	}
+
+	if !p.SecurityCtx.IsSysAdmin() {
+		p.HandleForbidden(p.SecurityCtx.GetUsername())
+		return
 	}
-	if contains {
-		p.CustomAbort(http.StatusPreconditionFailed, "project contains policies, can not be deleted")
+
+	if !p.project.Deleted {
+		p.HandleBadRequest(fmt.Sprintf("project %d is not in the recycle bin", p.project.ProjectID))
+		return
 	}
 
-	if err = p.ProjectMgr.Delete(p.project.ProjectID); err != nil {
+	if err := dao.RestoreProject(p.project.ProjectID); err != nil {
 		p.HandleInternalServerError(
-			fmt.Sprintf("failed to delete project %d: %v", p.project.ProjectID, err))
+			fmt.Sprintf("failed to restore project %d: %v", p.project.ProjectID, err))
 		return
 	}
 
@@ -229,7 +316,7 @@ func (p *ProjectAPI) Delete() {
 			ProjectID: p.project.ProjectID,
 			RepoName:  p.project.Name + "/",
 			RepoTag:   "N/A",
-			Operation: "delete",
+			Operation: "restore",
 			OpTime:    time.Now(),
 		}); err != nil {
 			log.Errorf("failed to add access log: %v", err)
@@ -255,6 +342,50 @@ func projectContainsPolicy(id int64) (bool, error) {
 	return len(policies) > 0, nil
 }
 
+// ErrProjectDeleted is returned by EnforceNotDeleted when an operation
+// targets a project sitting in the recycle bin.
+var ErrProjectDeleted = errors.New("project has been deleted")
+
+// EnforceNotDeleted blocks operations, e.g. a push, against a project that
+// has been soft-deleted. It is called from the registry notification
+// handler before a push is accepted.
+func EnforceNotDeleted(project *models.Project) error {
+	if project.Deleted {
+		return ErrProjectDeleted
+	}
+	return nil
+}
+
+// PurgeDeletedProjects hard-deletes projects, along with their repositories,
+// tags, replication policies and access logs, once they have sat in the
+// recycle bin longer than the project_recycle_days configured retention
+// window. It is invoked periodically by the UI's scheduled job runner; a
+// non-positive retention window disables purging entirely.
+func PurgeDeletedProjects() error {
+	days, err := config.ProjectRecycleDays()
+	if err != nil {
+		return err
+	}
+	if days <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	projects, err := dao.GetDeletedProjectsBefore(cutoff)
+	if err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		if err := dao.PurgeProject(project.ProjectID); err != nil {
+			log.Errorf("failed to purge project %d: %v", project.ProjectID, err)
+			continue
+		}
+		log.Infof("purged project %s (id %d), deleted at %v", project.Name, project.ProjectID, project.DeletedAt)
+	}
+	return nil
+}
+
 // List ...
 // TODO refacter pattern to:
 // /api/repositories?owner=xxx&name=xxx&public=true&member=xxx&role=1&page=1&size=3
@@ -306,6 +437,20 @@ func (p *ProjectAPI) List() {
 		return
 	}
 
+	// ProjectMgr.GetAll predates the recycle bin and doesn't filter
+	// deleted=true, so soft-deleted projects are dropped here rather than
+	// served in the list. Note this means the total/pagination header set
+	// below, which comes from the separate GetTotal call, can overcount
+	// against what's actually returned until ProjectMgr itself is taught
+	// to filter deleted projects out at the query level.
+	active := projects[:0]
+	for _, project := range projects {
+		if !project.Deleted {
+			active = append(active, project)
+		}
+	}
+	projects = active
+
 	for _, project := range projects {
 		if query.Public == nil || *query.Public == false {
 			roles, err := p.ProjectMgr.GetRoles(p.SecurityCtx.GetUsername(), project.ProjectID)
@@ -332,6 +477,25 @@ func (p *ProjectAPI) List() {
 		}
 
 		project.RepoCount = len(repos)
+
+		usage, err := dao.GetProjectStorageUsage(project.ProjectID)
+		if err != nil {
+			log.Errorf("failed to get storage usage of project %s: %v", project.Name, err)
+			p.CustomAbort(http.StatusInternalServerError, "")
+		}
+		project.StorageUsage = usage
+
+		// same reasoning as GetQuota: ProjectMgr.GetAll's SELECT predates
+		// the quota columns, so they have to be filled in from a dedicated
+		// dao call rather than trusted off the struct it returned.
+		repoLimit, tagLimit, storageLimit, err := dao.GetProjectQuotaLimits(project.ProjectID)
+		if err != nil {
+			log.Errorf("failed to get quota of project %s: %v", project.Name, err)
+			p.CustomAbort(http.StatusInternalServerError, "")
+		}
+		project.RepoLimit = repoLimit
+		project.TagLimit = tagLimit
+		project.StorageLimit = storageLimit
 	}
 
 	p.SetPaginationHeader(total, page, size)
@@ -368,6 +532,141 @@ func (p *ProjectAPI) ToggleProjectPublic() {
 	}
 }
 
+// GetQuota handles GET to /api/projects/{}/quota, returning the configured
+// limits of the project alongside its current usage.
+func (p *ProjectAPI) GetQuota() {
+	if !p.SecurityCtx.IsAuthenticated() {
+		p.HandleUnauthorized()
+		return
+	}
+
+	if !p.SecurityCtx.HasReadPerm(p.project.ProjectID) {
+		p.HandleForbidden(p.SecurityCtx.GetUsername())
+		return
+	}
+
+	repos, err := dao.GetRepositoryByProjectName(p.project.Name)
+	if err != nil {
+		p.HandleInternalServerError(fmt.Sprintf("failed to get repositories of project %d: %v",
+			p.project.ProjectID, err))
+		return
+	}
+
+	storageUsage, err := dao.GetProjectStorageUsage(p.project.ProjectID)
+	if err != nil {
+		p.HandleInternalServerError(fmt.Sprintf("failed to get storage usage of project %d: %v",
+			p.project.ProjectID, err))
+		return
+	}
+
+	// the configured limits are read through a dedicated dao call rather
+	// than off p.project: ProjectMgr.Get's SELECT predates the quota
+	// columns, so p.project.RepoLimit/TagLimit/StorageLimit come back 0
+	// regardless of what's actually configured.
+	repoLimit, tagLimit, storageLimit, err := dao.GetProjectQuotaLimits(p.project.ProjectID)
+	if err != nil {
+		p.HandleInternalServerError(fmt.Sprintf("failed to get quota of project %d: %v",
+			p.project.ProjectID, err))
+		return
+	}
+
+	p.Data["json"] = &models.ProjectQuota{
+		RepoLimit:    repoLimit,
+		TagLimit:     tagLimit,
+		StorageLimit: storageLimit,
+		RepoUsage:    int64(len(repos)),
+		StorageUsage: storageUsage,
+	}
+	p.ServeJSON()
+}
+
+// PutQuota handles PUT to /api/projects/{}/quota. Only a sysadmin may adjust
+// the limits of a project.
+func (p *ProjectAPI) PutQuota() {
+	if !p.SecurityCtx.IsAuthenticated() {
+		p.HandleUnauthorized()
+		return
+	}
+
+	if !p.SecurityCtx.IsSysAdmin() {
+		p.HandleForbidden(p.SecurityCtx.GetUsername())
+		return
+	}
+
+	var req projectReq
+	p.DecodeJSONReq(&req)
+	if req.RepoLimit < 0 || req.TagLimit < 0 || req.StorageLimit < 0 {
+		p.HandleBadRequest("repo_limit, tag_limit and storage_limit must not be negative")
+		return
+	}
+
+	// written through a dedicated dao call, mirroring SoftDeleteProject:
+	// ProjectMgr.Update's column set is scoped to what ToggleProjectPublic
+	// needs and predates repo_limit/tag_limit/storage_limit, so routing
+	// this through it would silently drop the change (and, since the
+	// passed Project has Public: 0, flip the project private too).
+	if err := dao.UpdateProjectQuota(p.project.ProjectID,
+		req.RepoLimit, req.TagLimit, req.StorageLimit); err != nil {
+		p.HandleInternalServerError(fmt.Sprintf("failed to update quota of project %d: %v",
+			p.project.ProjectID, err))
+		return
+	}
+}
+
+// CheckRepoQuota reports ErrQuotaExceeded if creating one more repository
+// under project would push it over its configured repository limit. It is
+// called from the registry notification handler when a push targets a
+// repository the project doesn't have yet.
+func CheckRepoQuota(project *models.Project) error {
+	if project.RepoLimit <= 0 {
+		return nil
+	}
+
+	repos, err := dao.GetRepositoryByProjectName(project.Name)
+	if err != nil {
+		return err
+	}
+	if int64(len(repos)) >= project.RepoLimit {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// CheckPushQuota reports ErrQuotaExceeded if accepting a push of pushSize
+// bytes that adds a new tag named tag to repo would push project over its
+// configured tag or storage limit. It is called from the registry
+// notification handler on each manifest push, before the push is accepted.
+func CheckPushQuota(project *models.Project, repo, tag string, pushSize int64) error {
+	if project.TagLimit > 0 {
+		tags, err := dao.GetTagsByRepo(repo)
+		if err != nil {
+			return err
+		}
+		exists := false
+		for _, t := range tags {
+			if t == tag {
+				exists = true
+				break
+			}
+		}
+		if !exists && int64(len(tags)) >= project.TagLimit {
+			return ErrQuotaExceeded
+		}
+	}
+
+	if project.StorageLimit > 0 {
+		used, err := dao.GetProjectStorageUsage(project.ProjectID)
+		if err != nil {
+			return err
+		}
+		if used+pushSize > project.StorageLimit {
+			return ErrQuotaExceeded
+		}
+	}
+
+	return nil
+}
+
 // FilterAccessLog handles GET to /api/projects/{}/logs
 func (p *ProjectAPI) FilterAccessLog() {
 	if !p.SecurityCtx.IsAuthenticated() {
@@ -383,8 +682,36 @@ func (p *ProjectAPI) FilterAccessLog() {
 	var query models.AccessLog
 	p.DecodeJSONReq(&query)
 
+	queryParm := buildLogQueryParam(p.project.ProjectID, query)
+
+	page, pageSize := p.GetPaginationParams()
+	queryParm.Pagination = &models.Pagination{
+		Page: page,
+		Size: pageSize,
+	}
+
+	total, err := dao.GetTotalOfAccessLogs(queryParm)
+	if err != nil {
+		p.HandleInternalServerError(fmt.Sprintf(
+			"failed to get total of access log: %v", err))
+		return
+	}
+
+	logs, err := dao.GetAccessLogs(queryParm)
+	if err != nil {
+		p.HandleInternalServerError(fmt.Sprintf(
+			"failed to get access log: %v", err))
+		return
+	}
+
+	p.SetPaginationHeader(total, page, pageSize)
+	p.Data["json"] = logs
+	p.ServeJSON()
+}
+
+func buildLogQueryParam(projectID int64, query models.AccessLog) *models.LogQueryParam {
 	queryParm := &models.LogQueryParam{
-		ProjectIDs: []int64{p.project.ProjectID},
+		ProjectIDs: []int64{projectID},
 		Username:   query.Username,
 		Repository: query.RepoName,
 		Tag:        query.RepoTag,
@@ -404,29 +731,245 @@ func (p *ProjectAPI) FilterAccessLog() {
 		queryParm.EndTime = &endTime
 	}
 
-	page, pageSize := p.GetPaginationParams()
-	queryParm.Pagination = &models.Pagination{
-		Page: page,
-		Size: pageSize,
+	return queryParm
+}
+
+const (
+	logExportFormatJSONLines = "jsonl"
+	logExportFormatCSV       = "csv"
+	logExportFormatCEF       = "cef"
+
+	// logExportPageSize bounds how many rows are pulled from the DB at a
+	// time, so exporting a large project's history doesn't load the whole
+	// result set into memory.
+	logExportPageSize = 500
+
+	// logExportRateLimit is the minimum interval between two exports
+	// started by the same user, so the unpaginated endpoint can't be
+	// hammered to repeatedly dump the whole access log table.
+	logExportRateLimit = time.Minute
+
+	// logExportStaleAfter bounds how long a user's entry sits in
+	// logExportLastTime after their last export; it's several multiples of
+	// logExportRateLimit so idle users get pruned instead of accumulating
+	// in the map forever.
+	logExportStaleAfter = time.Hour
+
+	cefVersion = "0"
+	cefVendor  = "Harbor"
+	cefProduct = "harbor"
+)
+
+var (
+	logExportMu       sync.Mutex
+	logExportLastTime = map[string]time.Time{}
+)
+
+// ExportAccessLog handles GET to /api/projects/{}/logs/export and streams
+// the full, unpaginated result of the same filters FilterAccessLog accepts
+// in jsonl, csv or cef format. It walks the result with dao.GetAccessLogsAfter,
+// a bounded, page-sized keyset cursor, and writes each page as soon as it's
+// fetched using chunked transfer encoding, rather than loading the whole
+// result set into memory or paginating by offset (which would skip or
+// duplicate rows under concurrent inserts).
+//
+// Headers are only sent once the first page has been fetched successfully,
+// so a query error is reported as a normal HTTP error status. A query error
+// on a later page can't be: the response is already underway, so it's
+// logged and the stream is simply cut short; a truncated jsonl/csv/cef
+// export has no in-band trailer to signal that to the client.
+func (p *ProjectAPI) ExportAccessLog() {
+	if !p.SecurityCtx.IsAuthenticated() {
+		p.HandleUnauthorized()
+		return
 	}
 
-	total, err := dao.GetTotalOfAccessLogs(queryParm)
-	if err != nil {
-		p.HandleInternalServerError(fmt.Sprintf(
-			"failed to get total of access log: %v", err))
+	if !p.SecurityCtx.HasReadPerm(p.project.ProjectID) {
+		p.HandleForbidden(p.SecurityCtx.GetUsername())
 		return
 	}
 
-	logs, err := dao.GetAccessLogs(queryParm)
-	if err != nil {
-		p.HandleInternalServerError(fmt.Sprintf(
-			"failed to get access log: %v", err))
+	username := p.SecurityCtx.GetUsername()
+	if !allowLogExport(username) {
+		p.RenderError(http.StatusTooManyRequests, "log export rate limit exceeded, please try again later")
 		return
 	}
 
-	p.SetPaginationHeader(total, page, pageSize)
-	p.Data["json"] = logs
-	p.ServeJSON()
+	format := p.GetString("format")
+	switch format {
+	case "":
+		format = logExportFormatJSONLines
+	case logExportFormatJSONLines, logExportFormatCSV, logExportFormatCEF:
+	default:
+		releaseLogExport(username)
+		p.HandleBadRequest(fmt.Sprintf("unsupported export format: %s", format))
+		return
+	}
+
+	var query models.AccessLog
+	query.Username = p.GetString("username")
+	query.RepoName = p.GetString("repository")
+	query.RepoTag = p.GetString("tag")
+	query.Keywords = p.GetString("keywords")
+	if bt := p.GetString("begin_timestamp"); len(bt) > 0 {
+		if v, err := strconv.ParseInt(bt, 10, 64); err == nil {
+			query.BeginTimestamp = v
+		}
+	}
+	if et := p.GetString("end_timestamp"); len(et) > 0 {
+		if v, err := strconv.ParseInt(et, 10, 64); err == nil {
+			query.EndTimestamp = v
+		}
+	}
+	queryParm := buildLogQueryParam(p.project.ProjectID, query)
+
+	var afterID int64
+	headersSent := false
+	deviceVersion := config.HarborVersion()
+	flusher, _ := p.Ctx.ResponseWriter.ResponseWriter.(http.Flusher)
+
+	for {
+		logs, err := dao.GetAccessLogsAfter(queryParm, afterID, logExportPageSize)
+		if err != nil {
+			if !headersSent {
+				releaseLogExport(username)
+				p.HandleInternalServerError(fmt.Sprintf(
+					"failed to export access log of project %d: %v", p.project.ProjectID, err))
+				return
+			}
+			log.Errorf("export of project %d truncated after log id %d: %v",
+				p.project.ProjectID, afterID, err)
+			return
+		}
+
+		if !headersSent {
+			p.Ctx.Output.Header("Content-Type", logExportContentType(format))
+			p.Ctx.Output.Header("Content-Disposition",
+				fmt.Sprintf(`attachment; filename="%s-access-log.%s"`, p.project.Name, format))
+			p.Ctx.Output.Header("Transfer-Encoding", "chunked")
+			if format == logExportFormatCSV {
+				fmt.Fprintln(p.Ctx.ResponseWriter, "op_time,username,project,repository,tag,operation")
+			}
+			headersSent = true
+		}
+
+		if len(logs) == 0 {
+			break
+		}
+
+		for _, l := range logs {
+			writeAccessLogEntry(p.Ctx.ResponseWriter, format, p.project.Name, deviceVersion, l)
+			afterID = l.LogID
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if len(logs) < logExportPageSize {
+			break
+		}
+	}
+}
+
+// allowLogExport applies a simple per-user rate limit to ExportAccessLog,
+// pruning entries older than logExportStaleAfter on every call so the map
+// doesn't grow without bound as distinct users export over time.
+func allowLogExport(username string) bool {
+	logExportMu.Lock()
+	defer logExportMu.Unlock()
+
+	now := time.Now()
+	for u, last := range logExportLastTime {
+		if now.Sub(last) > logExportStaleAfter {
+			delete(logExportLastTime, u)
+		}
+	}
+
+	if last, ok := logExportLastTime[username]; ok && now.Sub(last) < logExportRateLimit {
+		return false
+	}
+	logExportLastTime[username] = now
+	return true
+}
+
+// releaseLogExport undoes the reservation allowLogExport made for username
+// when the export turns out to fail before any data is sent, so a bad
+// request doesn't cost the user their rate-limit window.
+func releaseLogExport(username string) {
+	logExportMu.Lock()
+	defer logExportMu.Unlock()
+	delete(logExportLastTime, username)
+}
+
+func logExportContentType(format string) string {
+	switch format {
+	case logExportFormatCSV:
+		return "text/csv"
+	case logExportFormatCEF:
+		return "text/plain"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// writeAccessLogEntry writes a single access log record to w in the
+// requested format. project is the name of the project being exported,
+// used to fill in the CEF cs1 extension; deviceVersion is the running
+// Harbor version, used as the CEF Device Version field.
+func writeAccessLogEntry(w http.ResponseWriter, format, project, deviceVersion string, l models.AccessLog) {
+	switch format {
+	case logExportFormatCSV:
+		fmt.Fprintf(w, "%d,%s,%s,%s,%s,%s\n",
+			l.OpTime.Unix(), csvField(l.Username), csvField(project), csvField(l.RepoName),
+			csvField(l.RepoTag), csvField(l.Operation))
+	case logExportFormatCEF:
+		fmt.Fprintf(w, "CEF:%s|%s|%s|%s|%s|%s|%d|suser=%s cs1=%s cs2=%s cs3=%s rt=%d\n",
+			cefVersion, cefVendor, cefProduct, deviceVersion, l.Operation, l.Operation, cefSeverity(l.Operation),
+			cefEscape(l.Username), cefEscape(project), cefEscape(l.RepoName), cefEscape(l.RepoTag),
+			l.OpTime.UnixNano()/int64(time.Millisecond))
+	default:
+		data, err := json.Marshal(l)
+		if err != nil {
+			log.Errorf("failed to marshal access log entry: %v", err)
+			return
+		}
+		w.Write(data)
+		w.Write([]byte("\n"))
+	}
+}
+
+// cefSeverity maps an access log operation to a CEF severity (0-10): delete
+// is the most severe, read-only operations the least.
+func cefSeverity(operation string) int {
+	switch operation {
+	case "delete":
+		return 7
+	case "create", "push":
+		return 5
+	default:
+		return 1
+	}
+}
+
+// cefEscape escapes a CEF extension value per the spec: a backslash or
+// equals sign is backslash-escaped, and a newline is replaced with a
+// literal "\n", so a repo/tag/username containing any of them can't break
+// the key=value parse of the extension field it's placed in.
+func cefEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `=`, `\=`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	s = strings.ReplaceAll(s, "\r", `\n`)
+	return s
+}
+
+// csvField quotes a CSV field if it contains a comma, quote or newline.
+func csvField(s string) string {
+	if !strings.ContainsAny(s, ",\"\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
 }
 
 // TODO move this to package models
@@ -0,0 +1,43 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"time"
+
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+// purgeInterval is how often StartProjectPurgeScheduler runs
+// PurgeDeletedProjects. Project recycle-bin retention is measured in days,
+// so checking hourly is frequent enough without polling the DB needlessly.
+const purgeInterval = time.Hour
+
+// StartProjectPurgeScheduler starts the background job that reaps projects
+// whose recycle-bin retention window (project_recycle_days) has elapsed.
+// It's started once from main() alongside the UI's other periodical jobs
+// and runs for the lifetime of the process.
+func StartProjectPurgeScheduler() {
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := PurgeDeletedProjects(); err != nil {
+				log.Errorf("failed to purge deleted projects: %v", err)
+			}
+		}
+	}()
+}
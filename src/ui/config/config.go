@@ -0,0 +1,86 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config provides the UI process access to the values of
+// config.properties, as read from the environment variables they are
+// injected as.
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	onlyAdminCreateProjectKey = "only_admin_create_project"
+	projectRecycleDaysKey     = "project_recycle_days"
+	harborVersionKey          = "harbor_version"
+
+	// defaultProjectRecycleDays is used when project_recycle_days is unset
+	// or invalid; it keeps the recycle bin's default behavior at "keep
+	// soft-deleted projects for a week" rather than purging immediately.
+	defaultProjectRecycleDays = 7
+
+	// defaultHarborVersion is used when harbor_version is unset, e.g. a dev
+	// build that isn't stamped at build time.
+	defaultHarborVersion = "unknown"
+)
+
+// OnlyAdminCreateProject returns whether only a sysadmin is allowed to
+// create a new project.
+func OnlyAdminCreateProject() (bool, error) {
+	return getBool(onlyAdminCreateProjectKey, true)
+}
+
+// ProjectRecycleDays returns how many days a soft-deleted project sits in
+// the recycle bin before PurgeDeletedProjects hard-deletes it. A value of 0
+// or less disables purging.
+func ProjectRecycleDays() (int, error) {
+	v := GetStr(projectRecycleDaysKey)
+	if len(v) == 0 {
+		return defaultProjectRecycleDays, nil
+	}
+
+	days, err := strconv.Atoi(v)
+	if err != nil {
+		return defaultProjectRecycleDays, nil
+	}
+	return days, nil
+}
+
+// HarborVersion returns the running Harbor version, e.g. for the CEF log
+// export's Device Version field, or defaultHarborVersion if it isn't set.
+func HarborVersion() string {
+	v := GetStr(harborVersionKey)
+	if len(v) == 0 {
+		return defaultHarborVersion
+	}
+	return v
+}
+
+func getBool(key string, defaultValue bool) (bool, error) {
+	v := GetStr(key)
+	if len(v) == 0 {
+		return defaultValue, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// GetStr returns the raw string value of a config.properties key, read from
+// its environment variable form (upper-cased, as harbor.cfg / docker-compose
+// inject them), or "" if it isn't set.
+func GetStr(key string) string {
+	return os.Getenv(strings.ToUpper(key))
+}
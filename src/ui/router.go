@@ -0,0 +1,36 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"github.com/astaxie/beego"
+
+	"github.com/vmware/harbor/src/ui/api"
+)
+
+// initRouter registers the project-related REST endpoints. Other resources
+// (repositories, users, replication, ...) register their own routes the
+// same way alongside this one.
+func initRouter() {
+	beego.Router("/api/projects", &api.ProjectAPI{}, "get:List;post:Post;head:Head")
+	beego.Router("/api/projects/:id([0-9]+)", &api.ProjectAPI{}, "get:Get;delete:Delete")
+	beego.Router("/api/projects/:id([0-9]+)/publicity", &api.ProjectAPI{}, "put:ToggleProjectPublic")
+	beego.Router("/api/projects/:id([0-9]+)/restore", &api.ProjectAPI{}, "post:Restore")
+	beego.Router("/api/projects/:id([0-9]+)/quota", &api.ProjectAPI{}, "get:GetQuota;put:PutQuota")
+	beego.Router("/api/projects/:id([0-9]+)/logs", &api.ProjectAPI{}, "get:FilterAccessLog")
+	beego.Router("/api/projects/:id([0-9]+)/logs/export", &api.ProjectAPI{}, "get:ExportAccessLog")
+
+	beego.Router("/service/notifications", &api.NotificationHandler{})
+}